@@ -0,0 +1,51 @@
+//go:build !windows
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// openInRoot opens rootdir/relPath, refusing to follow any symlink - at
+// any path component, not just the leaf - that escapes rootdir (see
+// secureJoin). As a last line of defense against a rootfs mutating
+// between the containment check and the open, it also compares
+// device/inode from the Lstat used for containment against an Fstat of
+// the opened file, the same technique cri-o's idtools package uses.
+func openInRoot(rootdir, relPath string) (*os.File, error) {
+	full, err := secureJoin(rootdir, relPath)
+	if err != nil {
+		return nil, err
+	}
+
+	lst, err := os.Lstat(full)
+	if err != nil {
+		return nil, err
+	}
+	if lst.Mode()&os.ModeSymlink != 0 {
+		return nil, fmt.Errorf("refusing to open %s: resolved path is still a symlink", full)
+	}
+
+	f, err := os.Open(full)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	st, ok1 := fi.Sys().(*syscall.Stat_t)
+	lstSt, ok2 := lst.Sys().(*syscall.Stat_t)
+	if ok1 && ok2 {
+		if st.Dev != lstSt.Dev || st.Ino != lstSt.Ino {
+			f.Close()
+			return nil, fmt.Errorf("refusing to open %s: inode changed between lstat and open", full)
+		}
+	}
+
+	return f, nil
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package utils
+
+import (
+	"fmt"
+	"os"
+)
+
+// openInRoot opens rootdir/relPath, refusing to follow any symlink - at
+// any path component, not just the leaf - that escapes rootdir (see
+// secureJoin). Windows lacks the dev/ino comparison the Unix
+// implementation layers on top as a last defense against a TOCTOU race.
+func openInRoot(rootdir, relPath string) (*os.File, error) {
+	full, err := secureJoin(rootdir, relPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if lst, err := os.Lstat(full); err != nil {
+		return nil, err
+	} else if lst.Mode()&os.ModeSymlink != 0 {
+		return nil, fmt.Errorf("refusing to open %s: resolved path is still a symlink", full)
+	}
+
+	return os.Open(full)
+}
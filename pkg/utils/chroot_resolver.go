@@ -0,0 +1,286 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChrootResolver is a NameResolver that resolves uid/gid <-> name against
+// <rootdir>/etc/passwd and <rootdir>/etc/group instead of the host's own,
+// so callers that walk a container or image filesystem (backup, sync,
+// image introspection) can render correct uname/gname without chrooting
+// the daemon itself.
+//
+// The parsed tables are cached per rootdir and refreshed automatically
+// when either file's mtime changes.
+type ChrootResolver struct {
+	rootdir string
+
+	mutex       sync.Mutex
+	loaded      bool
+	passwdMtime time.Time
+	groupMtime  time.Time
+
+	uidToName    map[uint32]string
+	nameToUid    map[string]uint32
+	gidToName    map[uint32]string
+	nameToGid    map[string]uint32
+	primaryGid   map[uint32]uint32
+	groupMembers map[uint32][]string
+}
+
+// NewChrootResolver returns a NameResolver that resolves against
+// <rootdir>/etc/passwd and <rootdir>/etc/group.
+func NewChrootResolver(rootdir string) *ChrootResolver {
+	return &ChrootResolver{rootdir: rootdir}
+}
+
+func (r *ChrootResolver) UserName(uid uint32) string {
+	r.refresh()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if name, ok := r.uidToName[uid]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(uid), 10)
+}
+
+func (r *ChrootResolver) GroupName(gid uint32) string {
+	r.refresh()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if name, ok := r.gidToName[gid]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(gid), 10)
+}
+
+func (r *ChrootResolver) LookupUser(name string) int {
+	r.refresh()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if uid, ok := r.nameToUid[name]; ok {
+		return int(uid)
+	}
+	if uid, err := strconv.Atoi(name); err == nil {
+		return uid
+	}
+	return -1
+}
+
+func (r *ChrootResolver) LookupGroup(name string) int {
+	r.refresh()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if gid, ok := r.nameToGid[name]; ok {
+		return int(gid)
+	}
+	if gid, err := strconv.Atoi(name); err == nil {
+		return gid
+	}
+	return -1
+}
+
+// UserGroups returns every gid uid belongs to within this rootdir: its
+// primary gid from /etc/passwd plus every group in /etc/group whose
+// member list names it. Unlike the host resolver this isn't additionally
+// TTL-cached, since refresh() already only reparses the underlying files
+// when their mtimes change.
+func (r *ChrootResolver) UserGroups(uid uint32) []int {
+	r.refresh()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	seen := make(map[uint32]bool)
+	var gids []int
+	if pgid, ok := r.primaryGid[uid]; ok {
+		seen[pgid] = true
+		gids = append(gids, int(pgid))
+	}
+
+	if name, ok := r.uidToName[uid]; ok {
+		for gid, members := range r.groupMembers {
+			if seen[gid] {
+				continue
+			}
+			if StringContains(members, name) {
+				seen[gid] = true
+				gids = append(gids, int(gid))
+			}
+		}
+	}
+	return gids
+}
+
+// refresh (re)parses /etc/passwd and /etc/group under rootdir if either
+// is missing from the cache or has changed on disk since it was loaded.
+func (r *ChrootResolver) refresh() {
+	passwdMtime, _ := fileMtime(filepath.Join(r.rootdir, "etc", "passwd"))
+	groupMtime, _ := fileMtime(filepath.Join(r.rootdir, "etc", "group"))
+
+	r.mutex.Lock()
+	stale := !r.loaded || !passwdMtime.Equal(r.passwdMtime) || !groupMtime.Equal(r.groupMtime)
+	r.mutex.Unlock()
+	if !stale {
+		return
+	}
+
+	uidToName, nameToUid, primaryGid := parsePasswd(r.rootdir)
+	gidToName, nameToGid, groupMembers := parseGroup(r.rootdir)
+
+	r.mutex.Lock()
+	r.uidToName, r.nameToUid = uidToName, nameToUid
+	r.gidToName, r.nameToGid = gidToName, nameToGid
+	r.primaryGid, r.groupMembers = primaryGid, groupMembers
+	r.passwdMtime, r.groupMtime = passwdMtime, groupMtime
+	r.loaded = true
+	r.mutex.Unlock()
+}
+
+func fileMtime(path string) (time.Time, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fi.ModTime(), nil
+}
+
+// parsePasswd reads <rootdir>/etc/passwd, if present, returning uid<->name
+// maps and each uid's primary gid. A missing file simply yields empty
+// maps so callers fall back to numeric strings.
+func parsePasswd(rootdir string) (map[uint32]string, map[string]uint32, map[uint32]uint32) {
+	uidToName := make(map[uint32]string)
+	nameToUid := make(map[string]uint32)
+	primaryGid := make(map[uint32]uint32)
+
+	f, err := openInRoot(rootdir, "etc/passwd")
+	if err != nil {
+		return uidToName, nameToUid, primaryGid
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// name:passwd:uid:gid:gecos:home:shell
+		fields := strings.Split(line, ":")
+		if len(fields) < 4 {
+			continue
+		}
+		uid, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			continue
+		}
+		uidToName[uint32(uid)] = fields[0]
+		nameToUid[fields[0]] = uint32(uid)
+		if gid, err := strconv.ParseUint(fields[3], 10, 32); err == nil {
+			primaryGid[uint32(uid)] = uint32(gid)
+		}
+	}
+	return uidToName, nameToUid, primaryGid
+}
+
+// parseGroup reads <rootdir>/etc/group, if present, returning gid<->name
+// maps and each gid's member user names. A missing file simply yields
+// empty maps so callers fall back to numeric strings.
+func parseGroup(rootdir string) (map[uint32]string, map[string]uint32, map[uint32][]string) {
+	gidToName := make(map[uint32]string)
+	nameToGid := make(map[string]uint32)
+	groupMembers := make(map[uint32][]string)
+
+	f, err := openInRoot(rootdir, "etc/group")
+	if err != nil {
+		return gidToName, nameToGid, groupMembers
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// name:passwd:gid:members
+		fields := strings.Split(line, ":")
+		if len(fields) < 4 {
+			continue
+		}
+		gid, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			continue
+		}
+		gidToName[uint32(gid)] = fields[0]
+		nameToGid[fields[0]] = uint32(gid)
+		if fields[3] != "" {
+			groupMembers[uint32(gid)] = strings.Split(fields[3], ",")
+		}
+	}
+	return gidToName, nameToGid, groupMembers
+}
+
+// secureJoin resolves rootdir/relPath component by component, rejecting
+// any symlink - at any depth, not just the final component - that would
+// resolve outside of rootdir. This is the containment check cri-o's
+// idtools package applies before trusting a container image's files:
+// Lstat-ing only the leaf path is not enough, since a malicious image
+// can make an intermediate directory (e.g. "etc") itself a symlink that
+// the kernel follows transparently before the leaf is ever touched.
+func secureJoin(rootdir, relPath string) (string, error) {
+	rootdir, err := filepath.Abs(rootdir)
+	if err != nil {
+		return "", err
+	}
+
+	current := rootdir
+	for _, component := range strings.Split(filepath.Clean(relPath), string(filepath.Separator)) {
+		if component == "" || component == "." {
+			continue
+		}
+
+		next := filepath.Join(current, component)
+		lst, err := os.Lstat(next)
+		if err != nil {
+			return "", err
+		}
+
+		if lst.Mode()&os.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+
+		target, err := os.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(current, target)
+		}
+		resolved, err := filepath.EvalSymlinks(target)
+		if err != nil {
+			return "", err
+		}
+		if err := containedIn(rootdir, resolved); err != nil {
+			return "", fmt.Errorf("refusing to follow symlink %s: %w", next, err)
+		}
+		current = resolved
+	}
+
+	return current, nil
+}
+
+func containedIn(rootdir, path string) error {
+	rel, err := filepath.Rel(rootdir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s escapes %s", path, rootdir)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+//go:build !windows
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenInRootOrdinaryFile(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "etc", "passwd"), []byte("root:x:0:0::/root:/bin/sh\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := openInRoot(root, "etc/passwd")
+	if err != nil {
+		t.Fatalf("openInRoot on a normal file: %v", err)
+	}
+	f.Close()
+}
+
+func TestOpenInRootRejectsEscapingDirSymlink(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "passwd"), []byte("root:x:0:0::/root:/bin/sh\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// <root>/etc is itself a symlink escaping root; a leaf-only Lstat
+	// check would miss this since "passwd" isn't a symlink.
+	if err := os.Symlink(outside, filepath.Join(root, "etc")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := openInRoot(root, "etc/passwd"); err == nil {
+		t.Fatal("expected openInRoot to reject an intermediate symlink escaping rootdir")
+	}
+}
+
+func TestOpenInRootRejectsEscapingLeafSymlink(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	target := filepath.Join(outside, "passwd")
+	if err := os.WriteFile(target, []byte("root:x:0:0::/root:/bin/sh\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, filepath.Join(root, "etc", "passwd")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := openInRoot(root, "etc/passwd"); err == nil {
+		t.Fatal("expected openInRoot to reject a leaf symlink escaping rootdir")
+	}
+}
+
+func TestChrootResolverUserGroupsMergesPrimaryAndSupplementary(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	passwd := "alice:x:1000:1000::/home/alice:/bin/sh\n"
+	group := "alice:x:1000:\ndocker:x:999:alice,bob\nwheel:x:10:bob\n"
+	if err := os.WriteFile(filepath.Join(root, "etc", "passwd"), []byte(passwd), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "etc", "group"), []byte(group), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewChrootResolver(root)
+	gids := r.UserGroups(1000)
+
+	want := map[int]bool{1000: true, 999: true}
+	if len(gids) != len(want) {
+		t.Fatalf("UserGroups(1000) = %v, want primary 1000 plus supplementary 999", gids)
+	}
+	for _, g := range gids {
+		if !want[g] {
+			t.Fatalf("UserGroups(1000) = %v, unexpected gid %d", gids, g)
+		}
+	}
+
+	orig := defaultResolver
+	SetDefaultResolver(r)
+	defer SetDefaultResolver(orig)
+
+	if !InGroup(1000, 999) {
+		t.Fatal("InGroup(1000, 999) = false, want true (alice is a supplementary member of docker)")
+	}
+	if InGroup(1000, 10) {
+		t.Fatal("InGroup(1000, 10) = true, want false (alice isn't in wheel)")
+	}
+}
+
+func TestChrootResolverIgnoresEscapingSymlink(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "passwd"), []byte("root:x:0:0::/root:/bin/sh\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "etc")); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewChrootResolver(root)
+	if name := r.UserName(0); name != "0" {
+		t.Fatalf("UserName(0) = %q, want the numeric fallback since /etc/passwd should be unreachable", name)
+	}
+}
@@ -0,0 +1,35 @@
+package utils
+
+import "time"
+
+// groupsCacheTTL bounds how long a resolver's UserGroups result is
+// trusted before being recomputed, so group membership changes (adding
+// a user to a group) propagate without restarting the process.
+const groupsCacheTTL = 30 * time.Second
+
+type groupsCacheEntry struct {
+	gids      []int
+	expiresAt time.Time
+}
+
+// UserGroups returns every gid uid belongs to, primary group first
+// followed by supplementary groups, resolved against whichever
+// NameResolver is currently active - the host by default, or a
+// ChrootResolver's rootdir once one has been installed via
+// SetDefaultResolver.
+func UserGroups(uid int) []int {
+	return defaultResolver.UserGroups(uint32(uid))
+}
+
+// InGroup reports whether uid belongs to gid, either as its primary
+// group or a supplementary one. Callers use this to make ACL-style
+// decisions (e.g. "can uid read a file owned by group X") without
+// forking `id` or reimplementing group membership at each call site.
+func InGroup(uid, gid int) bool {
+	for _, g := range UserGroups(uid) {
+		if g == gid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+//go:build cgo && !windows
+
+package utils
+
+/*
+#include <grp.h>
+#include <stdlib.h>
+#include <sys/types.h>
+*/
+import "C"
+
+import (
+	"os/user"
+	"strconv"
+	"unsafe"
+)
+
+// lookupUserGroups asks the system's NSS stack for uid's full group list
+// via getgrouplist(3), so results stay correct when groups come from
+// LDAP/SSSD rather than flat /etc/group files.
+func lookupUserGroups(uid uint32) []int {
+	u, err := user.LookupId(strconv.FormatUint(uint64(uid), 10))
+	if err != nil {
+		logger.Warnf("lookup uid %d: %s", uid, err)
+		return nil
+	}
+	primaryGid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		logger.Warnf("parse gid %q for uid %d: %s", u.Gid, uid, err)
+		return nil
+	}
+
+	cName := C.CString(u.Username)
+	defer C.free(unsafe.Pointer(cName))
+
+	ngroups := C.int(64)
+	for {
+		buf := make([]C.gid_t, int(ngroups))
+		n := ngroups
+		ret := C.getgrouplist(cName, C.gid_t(primaryGid), (*C.gid_t)(unsafe.Pointer(&buf[0])), &n)
+		if ret >= 0 {
+			gids := make([]int, 0, int(n))
+			for i := 0; i < int(n); i++ {
+				gids = append(gids, int(buf[i]))
+			}
+			return gids
+		}
+		// buf was too small; n now holds the number of groups needed.
+		ngroups = n
+	}
+}
@@ -0,0 +1,77 @@
+//go:build !windows && !cgo
+
+package utils
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// lookupUserGroups walks /etc/passwd for uid's primary gid and /etc/group
+// for every group whose member list names it, without cgo. When cgo is
+// available groups_cgo.go's getgrouplist(3)-backed version is used
+// instead, since it also picks up NSS/LDAP/SSSD-provided groups that
+// /etc/group alone won't have.
+func lookupUserGroups(uid uint32) []int {
+	name := UserName(uid)
+	seen := make(map[int]bool)
+	var gids []int
+
+	if gid, ok := primaryGid(name); ok {
+		seen[gid] = true
+		gids = append(gids, gid)
+	}
+
+	f, err := os.Open("/etc/group")
+	if err != nil {
+		logger.Warnf("open /etc/group: %s", err)
+		return gids
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// name:passwd:gid:members
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 4 {
+			continue
+		}
+		gid, err := strconv.Atoi(fields[2])
+		if err != nil || seen[gid] {
+			continue
+		}
+		for _, member := range strings.Split(fields[3], ",") {
+			if member == name {
+				seen[gid] = true
+				gids = append(gids, gid)
+				break
+			}
+		}
+	}
+	return gids
+}
+
+func primaryGid(name string) (int, bool) {
+	f, err := os.Open("/etc/passwd")
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// name:passwd:uid:gid:gecos:home:shell
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 4 || fields[0] != name {
+			continue
+		}
+		gid, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return 0, false
+		}
+		return gid, true
+	}
+	return 0, false
+}
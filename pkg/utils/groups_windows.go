@@ -0,0 +1,11 @@
+//go:build windows
+
+package utils
+
+// lookupUserGroups has no POSIX supplementary-group concept to draw on:
+// Windows has no /etc/group and no getgrouplist(3). uid is reported as
+// its own sole (synthetic) group; SID-based group membership is not
+// consulted.
+func lookupUserGroups(uid uint32) []int {
+	return []int{int(uid)}
+}
@@ -0,0 +1,206 @@
+//go:build !windows
+
+package utils
+
+import (
+	"os/user"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// positiveIDCacheTTL and negativeIDCacheTTL bound how long a resolved or
+// failed uid/gid lookup is trusted before hostResolver asks the host's
+// user database again. Negative entries expire much sooner so a user or
+// group created after a failed lookup shows up quickly, while positive
+// entries live long enough that a directory walk touching millions of
+// files doesn't re-resolve the same handful of owners over and over.
+const (
+	positiveIDCacheTTL = 5 * time.Minute
+	negativeIDCacheTTL = 30 * time.Second
+)
+
+type idCacheEntry struct {
+	value     string
+	negative  bool
+	expiresAt time.Time
+}
+
+type nameCacheEntry struct {
+	id        int
+	negative  bool
+	expiresAt time.Time
+}
+
+// hostResolver is the NameResolver backed by the host's own os/user
+// database. Lookups are cached with separate positive/negative TTLs
+// under a RWMutex so concurrent readers (e.g. during a large directory
+// walk) don't serialize on a single mutex.
+type hostResolver struct {
+	mutex      sync.RWMutex
+	uids       map[uint32]idCacheEntry
+	gids       map[uint32]idCacheEntry
+	users      map[string]nameCacheEntry
+	groups     map[string]nameCacheEntry
+	membership map[uint32]groupsCacheEntry
+}
+
+func newHostResolver() *hostResolver {
+	return &hostResolver{
+		uids:       make(map[uint32]idCacheEntry),
+		gids:       make(map[uint32]idCacheEntry),
+		users:      make(map[string]nameCacheEntry),
+		groups:     make(map[string]nameCacheEntry),
+		membership: make(map[uint32]groupsCacheEntry),
+	}
+}
+
+// UserGroups returns every gid uid belongs to, cached under the same
+// mutex as uids/gids for groupsCacheTTL so membership changes propagate
+// without restarting the process.
+func (r *hostResolver) UserGroups(uid uint32) []int {
+	r.mutex.RLock()
+	if e, ok := r.membership[uid]; ok && time.Now().Before(e.expiresAt) {
+		r.mutex.RUnlock()
+		return e.gids
+	}
+	r.mutex.RUnlock()
+
+	gids := lookupUserGroups(uid)
+
+	r.mutex.Lock()
+	r.membership[uid] = groupsCacheEntry{gids: gids, expiresAt: time.Now().Add(groupsCacheTTL)}
+	r.mutex.Unlock()
+	return gids
+}
+
+func (r *hostResolver) UserName(uid uint32) string {
+	r.mutex.RLock()
+	if e, ok := r.uids[uid]; ok && time.Now().Before(e.expiresAt) {
+		r.mutex.RUnlock()
+		return e.value
+	}
+	r.mutex.RUnlock()
+
+	name := strconv.FormatUint(uint64(uid), 10)
+	ttl := negativeIDCacheTTL
+	negative := true
+	if u, err := user.LookupId(name); err == nil {
+		name = u.Username
+		ttl = positiveIDCacheTTL
+		negative = false
+	} else {
+		logger.Warnf("lookup uid %d: %s", uid, err)
+	}
+
+	r.mutex.Lock()
+	r.uids[uid] = idCacheEntry{value: name, negative: negative, expiresAt: time.Now().Add(ttl)}
+	r.mutex.Unlock()
+	return name
+}
+
+func (r *hostResolver) GroupName(gid uint32) string {
+	r.mutex.RLock()
+	if e, ok := r.gids[gid]; ok && time.Now().Before(e.expiresAt) {
+		r.mutex.RUnlock()
+		return e.value
+	}
+	r.mutex.RUnlock()
+
+	name := strconv.FormatUint(uint64(gid), 10)
+	ttl := negativeIDCacheTTL
+	negative := true
+	if g, err := user.LookupGroupId(name); err == nil {
+		name = g.Name
+		ttl = positiveIDCacheTTL
+		negative = false
+	} else {
+		logger.Warnf("lookup gid %d: %s", gid, err)
+	}
+
+	r.mutex.Lock()
+	r.gids[gid] = idCacheEntry{value: name, negative: negative, expiresAt: time.Now().Add(ttl)}
+	r.mutex.Unlock()
+	return name
+}
+
+func (r *hostResolver) LookupUser(name string) int {
+	r.mutex.RLock()
+	if e, ok := r.users[name]; ok && time.Now().Before(e.expiresAt) {
+		r.mutex.RUnlock()
+		return e.id
+	}
+	r.mutex.RUnlock()
+
+	uid := -1
+	ttl := negativeIDCacheTTL
+	negative := true
+	if u, err := user.Lookup(name); err == nil {
+		uid, _ = strconv.Atoi(u.Uid)
+		ttl = positiveIDCacheTTL
+		negative = false
+	} else if g, e := strconv.Atoi(name); e == nil {
+		uid = g
+		ttl = positiveIDCacheTTL
+		negative = false
+	} else {
+		logger.Warnf("lookup user %s: %s", name, err)
+	}
+
+	r.mutex.Lock()
+	r.users[name] = nameCacheEntry{id: uid, negative: negative, expiresAt: time.Now().Add(ttl)}
+	r.mutex.Unlock()
+	return uid
+}
+
+func (r *hostResolver) LookupGroup(name string) int {
+	r.mutex.RLock()
+	if e, ok := r.groups[name]; ok && time.Now().Before(e.expiresAt) {
+		r.mutex.RUnlock()
+		return e.id
+	}
+	r.mutex.RUnlock()
+
+	gid := -1
+	ttl := negativeIDCacheTTL
+	negative := true
+	if u, err := user.LookupGroup(name); err == nil {
+		gid, _ = strconv.Atoi(u.Gid)
+		ttl = positiveIDCacheTTL
+		negative = false
+	} else if g, e := strconv.Atoi(name); e == nil {
+		gid = g
+		ttl = positiveIDCacheTTL
+		negative = false
+	} else {
+		logger.Warnf("lookup group %s: %s", name, err)
+	}
+
+	r.mutex.Lock()
+	r.groups[name] = nameCacheEntry{id: gid, negative: negative, expiresAt: time.Now().Add(ttl)}
+	r.mutex.Unlock()
+	return gid
+}
+
+func (r *hostResolver) invalidateUser(uid uint32) {
+	r.mutex.Lock()
+	delete(r.uids, uid)
+	delete(r.membership, uid)
+	r.mutex.Unlock()
+}
+
+func (r *hostResolver) invalidateGroup(gid uint32) {
+	r.mutex.Lock()
+	delete(r.gids, gid)
+	r.mutex.Unlock()
+}
+
+func (r *hostResolver) reset() {
+	r.mutex.Lock()
+	r.uids = make(map[uint32]idCacheEntry)
+	r.gids = make(map[uint32]idCacheEntry)
+	r.users = make(map[string]nameCacheEntry)
+	r.groups = make(map[string]nameCacheEntry)
+	r.membership = make(map[uint32]groupsCacheEntry)
+	r.mutex.Unlock()
+}
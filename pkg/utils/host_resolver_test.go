@@ -0,0 +1,79 @@
+//go:build !windows
+
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNegativeIDCacheTTLShorterThanPositive(t *testing.T) {
+	if negativeIDCacheTTL >= positiveIDCacheTTL {
+		t.Fatalf("negativeIDCacheTTL (%s) should be shorter than positiveIDCacheTTL (%s) so a user or group created after a failed lookup shows up quickly", negativeIDCacheTTL, positiveIDCacheTTL)
+	}
+}
+
+func TestHostResolverServesFreshCacheEntryWithoutRelookup(t *testing.T) {
+	r := newHostResolver()
+	r.uids[999999] = idCacheEntry{value: "cached-name", expiresAt: time.Now().Add(time.Hour)}
+
+	if got := r.UserName(999999); got != "cached-name" {
+		t.Fatalf("UserName(999999) = %q, want the still-fresh cached value %q", got, "cached-name")
+	}
+}
+
+func TestHostResolverRelooksUpExpiredCacheEntry(t *testing.T) {
+	r := newHostResolver()
+	r.uids[999999] = idCacheEntry{value: "stale-name", expiresAt: time.Now().Add(-time.Second)}
+
+	// uid 999999 shouldn't resolve on any real host, so an expired entry
+	// must fall through to a fresh negative lookup rather than being
+	// served stale.
+	if got := r.UserName(999999); got == "stale-name" {
+		t.Fatalf("UserName(999999) returned the expired cache entry %q instead of re-resolving", got)
+	}
+}
+
+func TestHostResolverInvalidateUserDropsOnlyThatEntry(t *testing.T) {
+	r := newHostResolver()
+	r.uids[1] = idCacheEntry{value: "one", expiresAt: time.Now().Add(time.Hour)}
+	r.uids[2] = idCacheEntry{value: "two", expiresAt: time.Now().Add(time.Hour)}
+	r.membership[1] = groupsCacheEntry{gids: []int{1}, expiresAt: time.Now().Add(time.Hour)}
+
+	r.invalidateUser(1)
+
+	if _, ok := r.uids[1]; ok {
+		t.Fatal("invalidateUser(1) left uid 1 cached")
+	}
+	if _, ok := r.membership[1]; ok {
+		t.Fatal("invalidateUser(1) left uid 1's group membership cached")
+	}
+	if e, ok := r.uids[2]; !ok || e.value != "two" {
+		t.Fatal("invalidateUser(1) evicted an unrelated uid")
+	}
+}
+
+func TestResetIDCachesClearsHostResolver(t *testing.T) {
+	orig := defaultResolver
+	r := newHostResolver()
+	SetDefaultResolver(r)
+	defer SetDefaultResolver(orig)
+
+	r.uids[1] = idCacheEntry{value: "one", expiresAt: time.Now().Add(time.Hour)}
+	r.membership[1] = groupsCacheEntry{gids: []int{1}, expiresAt: time.Now().Add(time.Hour)}
+
+	ResetIDCaches()
+
+	if len(r.uids) != 0 || len(r.membership) != 0 {
+		t.Fatalf("ResetIDCaches left state behind: uids=%v membership=%v", r.uids, r.membership)
+	}
+}
+
+func TestHostResolverUserGroupsCachesUnderGroupsCacheTTL(t *testing.T) {
+	r := newHostResolver()
+	r.membership[42] = groupsCacheEntry{gids: []int{7, 8}, expiresAt: time.Now().Add(groupsCacheTTL)}
+
+	if got := r.UserGroups(42); len(got) != 2 || got[0] != 7 || got[1] != 8 {
+		t.Fatalf("UserGroups(42) = %v, want the cached [7 8] without a fresh lookup", got)
+	}
+}
@@ -0,0 +1,208 @@
+//go:build windows
+
+package utils
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// hostResolver on Windows resolves kos's own synthetic uids/gids against
+// the on-disk idmap.json rather than the host's account database, since
+// os/user.LookupId there expects a SID string, not a numeric id. Real
+// SIDs are handled separately via UserNameSID.
+type hostResolver struct {
+	mutex      sync.Mutex
+	loaded     bool
+	mapMtime   time.Time
+	uidToName  map[uint32]string
+	nameToUid  map[string]uint32
+	gidToName  map[uint32]string
+	nameToGid  map[string]uint32
+	membership map[uint32]groupsCacheEntry
+}
+
+func newHostResolver() *hostResolver {
+	return &hostResolver{membership: make(map[uint32]groupsCacheEntry)}
+}
+
+// UserGroups returns every gid uid belongs to, cached for groupsCacheTTL.
+func (r *hostResolver) UserGroups(uid uint32) []int {
+	r.mutex.Lock()
+	if e, ok := r.membership[uid]; ok && time.Now().Before(e.expiresAt) {
+		r.mutex.Unlock()
+		return e.gids
+	}
+	r.mutex.Unlock()
+
+	gids := lookupUserGroups(uid)
+
+	r.mutex.Lock()
+	r.membership[uid] = groupsCacheEntry{gids: gids, expiresAt: time.Now().Add(groupsCacheTTL)}
+	r.mutex.Unlock()
+	return gids
+}
+
+func (r *hostResolver) UserName(uid uint32) string {
+	r.refresh()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if name, ok := r.uidToName[uid]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(uid), 10)
+}
+
+func (r *hostResolver) GroupName(gid uint32) string {
+	r.refresh()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if name, ok := r.gidToName[gid]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(gid), 10)
+}
+
+func (r *hostResolver) LookupUser(name string) int {
+	r.refresh()
+	r.mutex.Lock()
+	uid, ok := r.nameToUid[name]
+	r.mutex.Unlock()
+	if ok {
+		return int(uid)
+	}
+	if uid, err := strconv.Atoi(name); err == nil {
+		return uid
+	}
+	return r.allocate(name, true)
+}
+
+func (r *hostResolver) LookupGroup(name string) int {
+	r.refresh()
+	r.mutex.Lock()
+	gid, ok := r.nameToGid[name]
+	r.mutex.Unlock()
+	if ok {
+		return int(gid)
+	}
+	if gid, err := strconv.Atoi(name); err == nil {
+		return gid
+	}
+	return r.allocate(name, false)
+}
+
+// allocate assigns name the next free synthetic id and persists it to
+// idmap.json so the mapping survives process restarts. If name was
+// already allocated by a concurrent caller that raced this one between
+// the cache check in LookupUser/LookupGroup and this call, the existing
+// id is reused instead of minting a second one for the same name.
+func (r *hostResolver) allocate(name string, isUser bool) int {
+	idMapMutex.Lock()
+	defer idMapMutex.Unlock()
+
+	m, err := loadIDMap()
+	if err != nil {
+		logger.Warnf("load idmap: %s", err)
+		return -1
+	}
+
+	entries := m.Groups
+	if isUser {
+		entries = m.Users
+	}
+	for _, e := range entries {
+		if e.Name == name {
+			r.mutex.Lock()
+			r.loaded = false // force a reload from disk on next use
+			r.mutex.Unlock()
+			return int(e.ID)
+		}
+	}
+
+	var id uint32
+	if isUser {
+		id = m.NextUID
+		m.NextUID++
+		m.Users = append(m.Users, idMapEntry{ID: id, Name: name})
+	} else {
+		id = m.NextGID
+		m.NextGID++
+		m.Groups = append(m.Groups, idMapEntry{ID: id, Name: name})
+	}
+
+	if err := saveIDMap(m); err != nil {
+		logger.Warnf("save idmap: %s", err)
+		return -1
+	}
+
+	r.mutex.Lock()
+	r.loaded = false // force a reload from disk on next use
+	r.mutex.Unlock()
+
+	return int(id)
+}
+
+func (r *hostResolver) invalidateUser(uid uint32) {
+	r.mutex.Lock()
+	r.loaded = false
+	delete(r.membership, uid)
+	r.mutex.Unlock()
+}
+
+func (r *hostResolver) invalidateGroup(uint32) {
+	r.mutex.Lock()
+	r.loaded = false
+	r.mutex.Unlock()
+}
+
+func (r *hostResolver) reset() {
+	r.mutex.Lock()
+	r.loaded = false
+	r.uidToName, r.nameToUid = nil, nil
+	r.gidToName, r.nameToGid = nil, nil
+	r.membership = make(map[uint32]groupsCacheEntry)
+	r.mutex.Unlock()
+}
+
+// refresh (re)reads idmap.json if it hasn't been loaded yet or has
+// changed on disk since, mirroring ChrootResolver's mtime-based cache
+// invalidation.
+func (r *hostResolver) refresh() {
+	mtime, _ := fileMtime(idMapPath)
+
+	r.mutex.Lock()
+	stale := !r.loaded || !mtime.Equal(r.mapMtime)
+	r.mutex.Unlock()
+	if !stale {
+		return
+	}
+
+	idMapMutex.Lock()
+	m, err := loadIDMap()
+	idMapMutex.Unlock()
+	if err != nil {
+		logger.Warnf("load idmap: %s", err)
+		return
+	}
+
+	uidToName := make(map[uint32]string, len(m.Users))
+	nameToUid := make(map[string]uint32, len(m.Users))
+	for _, e := range m.Users {
+		uidToName[e.ID] = e.Name
+		nameToUid[e.Name] = e.ID
+	}
+	gidToName := make(map[uint32]string, len(m.Groups))
+	nameToGid := make(map[string]uint32, len(m.Groups))
+	for _, e := range m.Groups {
+		gidToName[e.ID] = e.Name
+		nameToGid[e.Name] = e.ID
+	}
+
+	r.mutex.Lock()
+	r.uidToName, r.nameToUid = uidToName, nameToUid
+	r.gidToName, r.nameToGid = gidToName, nameToGid
+	r.mapMtime = mtime
+	r.loaded = true
+	r.mutex.Unlock()
+}
@@ -0,0 +1,93 @@
+//go:build windows
+
+package utils
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func withTempIDMap(t *testing.T) {
+	t.Helper()
+	orig := idMapPath
+	idMapPath = filepath.Join(t.TempDir(), "idmap.json")
+	t.Cleanup(func() { idMapPath = orig })
+}
+
+func TestIDMapRoundTrip(t *testing.T) {
+	withTempIDMap(t)
+
+	m, err := loadIDMap()
+	if err != nil {
+		t.Fatalf("loadIDMap on missing file: %v", err)
+	}
+	if m.NextUID != firstSyntheticID || m.NextGID != firstSyntheticID {
+		t.Fatalf("loadIDMap on missing file = %+v, want fresh counters at %d", m, firstSyntheticID)
+	}
+
+	m.Users = append(m.Users, idMapEntry{ID: m.NextUID, Name: "alice"})
+	m.NextUID++
+	if err := saveIDMap(m); err != nil {
+		t.Fatalf("saveIDMap: %v", err)
+	}
+
+	reloaded, err := loadIDMap()
+	if err != nil {
+		t.Fatalf("loadIDMap after save: %v", err)
+	}
+	if len(reloaded.Users) != 1 || reloaded.Users[0].Name != "alice" {
+		t.Fatalf("loadIDMap after save = %+v, want one user alice", reloaded)
+	}
+	if reloaded.NextUID != firstSyntheticID+1 {
+		t.Fatalf("NextUID after save = %d, want %d", reloaded.NextUID, firstSyntheticID+1)
+	}
+}
+
+func TestAllocateReusesExistingName(t *testing.T) {
+	withTempIDMap(t)
+
+	r := newHostResolver()
+	first := r.allocate("alice", true)
+	second := r.allocate("alice", true)
+	if first != second {
+		t.Fatalf("allocate(%q) = %d, then %d; want the same id reused", "alice", first, second)
+	}
+
+	m, err := loadIDMap()
+	if err != nil {
+		t.Fatalf("loadIDMap: %v", err)
+	}
+	count := 0
+	for _, e := range m.Users {
+		if e.Name == "alice" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Fatalf("idmap.json has %d entries for %q, want exactly 1", count, "alice")
+	}
+}
+
+func TestAllocateConcurrentSameNameYieldsOneID(t *testing.T) {
+	withTempIDMap(t)
+
+	r := newHostResolver()
+	const n = 8
+	ids := make([]int, n)
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			ids[i] = r.allocate("bob", true)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, id := range ids {
+		if id != ids[0] {
+			t.Fatalf("allocate raced to different ids: ids[0]=%d ids[%d]=%d", ids[0], i, id)
+		}
+	}
+}
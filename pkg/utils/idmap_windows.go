@@ -0,0 +1,108 @@
+//go:build windows
+
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+)
+
+// idMapEntry is one row of the on-disk idmap.json that round-trips the
+// synthetic uids/gids kos's own POSIX emulation hands out on Windows,
+// where there is no numeric uid/gid concept to look up against.
+type idMapEntry struct {
+	ID   uint32 `json:"id"`
+	Name string `json:"name"`
+}
+
+type idMapFile struct {
+	NextUID uint32       `json:"nextUid"`
+	NextGID uint32       `json:"nextGid"`
+	Users   []idMapEntry `json:"users"`
+	Groups  []idMapEntry `json:"groups"`
+}
+
+// firstSyntheticID is where synthetic uid/gid allocation starts.
+const firstSyntheticID = 10000
+
+var idMapPath = func() string {
+	root := os.Getenv("PROGRAMDATA")
+	if root == "" {
+		root = `C:\ProgramData`
+	}
+	return filepath.Join(root, "kos", "idmap.json")
+}()
+
+// idMapMutex serializes read-modify-write access to idmap.json itself,
+// separate from hostResolver.mutex which only guards the in-memory
+// cache read from it.
+var idMapMutex sync.Mutex
+
+func loadIDMap() (*idMapFile, error) {
+	data, err := os.ReadFile(idMapPath)
+	if os.IsNotExist(err) {
+		return &idMapFile{NextUID: firstSyntheticID, NextGID: firstSyntheticID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m idMapFile
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	if m.NextUID == 0 {
+		m.NextUID = firstSyntheticID
+	}
+	if m.NextGID == 0 {
+		m.NextGID = firstSyntheticID
+	}
+	return &m, nil
+}
+
+func saveIDMap(m *idMapFile) error {
+	if err := os.MkdirAll(filepath.Dir(idMapPath), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(idMapPath, data, 0o644)
+}
+
+// UserNameSID resolves a real Windows user SID to its account name
+// directly through the host, bypassing kos's synthetic idmap.json
+// entirely. Use this when a caller already has a SID string rather than
+// one of kos's own numeric uids/gids. os/user.LookupId only accepts user
+// account SIDs, so a group SID, like any SID that fails to resolve, is
+// returned unchanged.
+func UserNameSID(sid string) string {
+	if u, err := user.LookupId(sid); err == nil {
+		return u.Username
+	} else {
+		logger.Warnf("lookup SID %s: %s", sid, err)
+	}
+	return sid
+}
+
+// wellKnownSIDs maps the handful of account names that archive writers
+// most often need to their well-known, locale-independent SID strings.
+var wellKnownSIDs = map[string]string{
+	"Administrators": "S-1-5-32-544",
+	"Users":          "S-1-5-32-545",
+	"Guests":         "S-1-5-32-546",
+	"SYSTEM":         "S-1-5-18",
+	"Everyone":       "S-1-1-0",
+}
+
+// WellKnownSID returns the well-known SID string for a handful of
+// locale-independent Windows account names (Administrators, Users,
+// SYSTEM, ...), or "" if name isn't one kos knows about. Tools emitting
+// tar/zip archives on Windows can use this to produce meaningful
+// uname/gname fields without a live lookup.
+func WellKnownSID(name string) string {
+	return wellKnownSIDs[name]
+}
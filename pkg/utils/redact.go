@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+)
+
+// defaultSensitiveQueryKeys are the query parameter names RedactURI
+// scrubs by default, matched case-insensitively. Covers presigned S3/GCS
+// URLs (signature, x-amz-signature) alongside plain password/token params.
+var defaultSensitiveQueryKeys = []string{
+	"password", "passwd", "token", "access_key", "secret", "sig", "signature", "x-amz-signature",
+}
+
+// RedactURI masks credentials in uri: the userinfo password, if any, and
+// the value of any query parameter whose key matches (case-insensitively)
+// defaultSensitiveQueryKeys or extraKeys. If uri doesn't parse as a URL,
+// it falls back to the legacy removePassword string munging so a
+// malformed URI doesn't get logged with its secret untouched.
+func RedactURI(uri string, extraKeys ...string) string {
+	u, err := url.Parse(uri)
+	schemeless := false
+	if (err != nil || u.Opaque != "") && !strings.Contains(uri, "://") {
+		// A schemeless "user:pass@host:port/db" DSN has no "://", so
+		// net/url treats everything before the first ":" as a scheme and
+		// the rest as Opaque rather than an authority, leaving u.User
+		// unpopulated. Reparse with a synthetic scheme so the same
+		// userinfo/query scrubbing below applies, then strip it back off.
+		if su, serr := url.Parse("x://" + uri); serr == nil && su.Opaque == "" {
+			u, err, schemeless = su, nil, true
+		}
+	}
+	if err != nil || u.Opaque != "" {
+		return removePassword(uri)
+	}
+
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "****")
+		}
+	}
+
+	if u.RawQuery != "" {
+		sensitive := make(map[string]bool, len(defaultSensitiveQueryKeys)+len(extraKeys))
+		for _, k := range defaultSensitiveQueryKeys {
+			sensitive[strings.ToLower(k)] = true
+		}
+		for _, k := range extraKeys {
+			sensitive[strings.ToLower(k)] = true
+		}
+
+		q := u.Query()
+		changed := false
+		for key := range q {
+			if sensitive[strings.ToLower(key)] {
+				q.Set(key, "****")
+				changed = true
+			}
+		}
+		if changed {
+			u.RawQuery = q.Encode()
+		}
+	}
+
+	// url.String() (for userinfo) and url.Values.Encode (for query values)
+	// percent-encode "*", turning
+	// our "****" mask into "%2A%2A%2A%2A". Undo that so redacted output
+	// stays human-readable.
+	out := strings.ReplaceAll(u.String(), "%2A%2A%2A%2A", "****")
+	if schemeless {
+		out = strings.TrimPrefix(out, "x://")
+	}
+	return out
+}
+
+// RemovePassword masks the userinfo password in uri. Prefer RedactURI,
+// which also scrubs sensitive query parameters such as presigned
+// signatures.
+func RemovePassword(uri string) string {
+	return RedactURI(uri)
+}
+
+// removePassword is the original hand-rolled implementation, kept as a
+// fallback for URIs that net/url refuses to parse.
+func removePassword(uri string) string {
+	p := strings.Index(uri, "@")
+	if p < 0 {
+		return uri
+	}
+	sp := strings.Index(uri, "://") + 3
+	if sp == 2 {
+		sp = 0
+	}
+	cp := strings.Index(uri[sp:], ":")
+	if cp < 0 || sp+cp > p {
+		return uri
+	}
+	return uri[:sp+cp] + ":****" + uri[p:]
+}
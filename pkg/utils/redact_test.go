@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactURI(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"s3://user:pass@host/bucket", "s3://user:****@host/bucket"},
+		{"https://host/path?password=hunter2&foo=bar", "https://host/path?foo=bar&password=****"},
+		{"https://host/path?X-Amz-Signature=abc123&other=1", "https://host/path?X-Amz-Signature=****&other=1"},
+		{"redis://:secret@localhost:6379/0", "redis://:****@localhost:6379/0"},
+		{"user:p@ssw0rd@host:5432/db?token=abcd", "user:****@host:5432/db?token=****"},
+		{"host:5432/db", "host:5432/db"},
+		{"https://host/path?foo=bar", "https://host/path?foo=bar"},
+	}
+	for _, c := range cases {
+		if got := RedactURI(c.in); got != c.want {
+			t.Errorf("RedactURI(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestRedactURISchemelessDSNScrubsSecrets(t *testing.T) {
+	got := RedactURI("user:p@ssw0rd@host:5432/db?token=abcd")
+	for _, leak := range []string{"ssw0rd", "abcd"} {
+		if strings.Contains(got, leak) {
+			t.Fatalf("RedactURI leaked %q into %q", leak, got)
+		}
+	}
+}
+
+func TestRedactURIExtraKeys(t *testing.T) {
+	got := RedactURI("https://host/path?custom_secret=abc", "custom_secret")
+	want := "https://host/path?custom_secret=****"
+	if got != want {
+		t.Errorf("RedactURI with extraKeys = %q, want %q", got, want)
+	}
+}
+
+func TestRemovePassword(t *testing.T) {
+	if got := RemovePassword("s3://user:pass@host/bucket"); got != "s3://user:****@host/bucket" {
+		t.Errorf("RemovePassword(...) = %q", got)
+	}
+}
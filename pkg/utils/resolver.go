@@ -0,0 +1,55 @@
+package utils
+
+// NameResolver resolves uid/gid to and from user/group names. The default
+// implementation looks up the host's own user/group database, but callers
+// that walk an arbitrary filesystem (backup, sync, image introspection)
+// can supply a resolver backed by that filesystem's /etc/passwd and
+// /etc/group instead, without chrooting the whole process.
+type NameResolver interface {
+	UserName(uid uint32) string
+	GroupName(gid uint32) string
+	LookupUser(name string) int
+	LookupGroup(name string) int
+
+	// UserGroups returns every gid uid belongs to (primary group first,
+	// then supplementary groups), resolved against the same rootdir as
+	// the other methods.
+	UserGroups(uid uint32) []int
+}
+
+// defaultResolver backs the package-level UserName/GroupName/LookupUser/
+// LookupGroup functions.
+var defaultResolver NameResolver = newHostResolver()
+
+// SetDefaultResolver overrides the resolver used by the package-level
+// UserName/GroupName/LookupUser/LookupGroup functions, e.g. with a
+// ChrootResolver while introspecting a container rootfs.
+func SetDefaultResolver(r NameResolver) {
+	defaultResolver = r
+}
+
+// InvalidateUser drops uid from the default resolver's cache, if it is
+// the host resolver, so the next lookup re-reads the host's
+// user database instead of returning a stale or negative answer.
+func InvalidateUser(uid uint32) {
+	if hr, ok := defaultResolver.(*hostResolver); ok {
+		hr.invalidateUser(uid)
+	}
+}
+
+// InvalidateGroup drops gid from the default resolver's cache, mirroring
+// InvalidateUser.
+func InvalidateGroup(gid uint32) {
+	if hr, ok := defaultResolver.(*hostResolver); ok {
+		hr.invalidateGroup(gid)
+	}
+}
+
+// ResetIDCaches clears every cached uid/gid/name lookup on the host
+// resolver. It's intended for tests that need a clean slate between
+// cases rather than for production use.
+func ResetIDCaches() {
+	if hr, ok := defaultResolver.(*hostResolver); ok {
+		hr.reset()
+	}
+}
@@ -5,12 +5,9 @@ import (
 	"mime"
 	"net"
 	"os"
-	"os/user"
 	"path"
 	"runtime"
-	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/mattn/go-isatty"
@@ -69,22 +66,6 @@ func WithTimeout(f func() error, timeout time.Duration) error {
 	return err
 }
 
-func RemovePassword(uri string) string {
-	p := strings.Index(uri, "@")
-	if p < 0 {
-		return uri
-	}
-	sp := strings.Index(uri, "://") + 3
-	if sp == 2 {
-		sp = 0
-	}
-	cp := strings.Index(uri[sp:], ":")
-	if cp < 0 || sp+cp > p {
-		return uri
-	}
-	return uri[:sp+cp] + ":****" + uri[p:]
-}
-
 func GuessMimeType(key string) string {
 	mimeType := mime.TypeByExtension(path.Ext(key))
 	if !strings.ContainsRune(mimeType, '/') {
@@ -119,82 +100,36 @@ func SupportANSIColor(fd uintptr) bool {
 	return isatty.IsTerminal(fd) && runtime.GOOS != "windows"
 }
 
-var uids = make(map[int]string)
-var gids = make(map[int]string)
-var users = make(map[string]int)
-var groups = make(map[string]int)
-var mutex sync.Mutex
-
 var logger = GetLogger("kos")
 
-func UserName(uid int) string {
-	mutex.Lock()
-	defer mutex.Unlock()
-	name, ok := uids[uid]
-	if !ok {
-		if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
-			name = u.Username
-		} else {
-			logger.Warnf("lookup uid %d: %s", uid, err)
-			name = strconv.Itoa(uid)
-		}
-		uids[uid] = name
-	}
-	return name
+// UserName resolves uid to a user name using the default NameResolver
+// (the host's os/user database, or kos's idmap.json on Windows, unless
+// overridden with SetDefaultResolver).
+// uid is a uint32 to match what callers typically have on hand from a
+// syscall.Stat_t.
+func UserName(uid uint32) string {
+	return defaultResolver.UserName(uid)
 }
 
-func GroupName(gid int) string {
-	mutex.Lock()
-	defer mutex.Unlock()
-	name, ok := gids[gid]
-	if !ok {
-		if g, err := user.LookupGroupId(strconv.Itoa(gid)); err == nil {
-			name = g.Name
-		} else {
-			logger.Warnf("lookup gid %d: %s", gid, err)
-			name = strconv.Itoa(gid)
-		}
-		gids[gid] = name
-	}
-	return name
+// GroupName resolves gid to a group name using the default NameResolver.
+func GroupName(gid uint32) string {
+	return defaultResolver.GroupName(gid)
 }
 
+// LookupUser resolves a user name to a uid using the default NameResolver.
+// It returns -1 if name cannot be resolved and is not itself numeric. On
+// Windows the host resolver instead assigns an unknown name a new
+// synthetic uid in idmap.json, returning -1 only if that file can't be
+// read or written.
 func LookupUser(name string) int {
-	mutex.Lock()
-	defer mutex.Unlock()
-	if u, ok := users[name]; ok {
-		return u
-	}
-	var uid = -1
-	if u, err := user.Lookup(name); err == nil {
-		uid, _ = strconv.Atoi(u.Uid)
-	} else {
-		if g, e := strconv.Atoi(name); e == nil {
-			uid = g
-		} else {
-			logger.Warnf("lookup user %s: %s", name, err)
-		}
-	}
-	users[name] = uid
-	return uid
+	return defaultResolver.LookupUser(name)
 }
 
+// LookupGroup resolves a group name to a gid using the default NameResolver.
+// It returns -1 if name cannot be resolved and is not itself numeric. On
+// Windows the host resolver instead assigns an unknown name a new
+// synthetic gid in idmap.json, returning -1 only if that file can't be
+// read or written.
 func LookupGroup(name string) int {
-	mutex.Lock()
-	defer mutex.Unlock()
-	if u, ok := groups[name]; ok {
-		return u
-	}
-	var gid = -1
-	if u, err := user.LookupGroup(name); err == nil {
-		gid, _ = strconv.Atoi(u.Gid)
-	} else {
-		if g, e := strconv.Atoi(name); e == nil {
-			gid = g
-		} else {
-			logger.Warnf("lookup group %s: %s", name, err)
-		}
-	}
-	groups[name] = gid
-	return gid
-}
\ No newline at end of file
+	return defaultResolver.LookupGroup(name)
+}